@@ -0,0 +1,74 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/cluster-operator/pkg/controller"
+)
+
+// FromCombinedCluster converts a namespaced cluster into its
+// cluster-scoped v1alpha2 representation, stashing the source namespace
+// in Spec.Namespace so ToCombinedCluster can restore it. It carries
+// across every field cluster-operator itself reads or writes
+// (Spec.ClusterNetwork, Spec.ProviderSpec, and the ClusterProviderStatus
+// fields), but not cluster-api Cluster.Status fields (APIEndpoints,
+// ErrorReason, ErrorMessage) that cluster-operator does not use; those
+// are not part of ClusterStatus and are dropped by the round trip.
+func FromCombinedCluster(cluster *controller.CombinedCluster) *Cluster {
+	out := &Cluster{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: SchemeGroupVersion.String(),
+			Kind:       "Cluster",
+		},
+		ObjectMeta: *cluster.ObjectMeta.DeepCopy(),
+		Spec: ClusterSpec{
+			Namespace:      cluster.Namespace,
+			ClusterNetwork: cluster.Spec.ClusterNetwork,
+			ProviderSpec:   cluster.Spec.ProviderSpec,
+		},
+		Status: ClusterStatus{
+			Conditions:               cluster.ClusterProviderStatus.Conditions,
+			Provisioned:              cluster.ClusterProviderStatus.Provisioned,
+			ProvisionedJobGeneration: cluster.ClusterProviderStatus.ProvisionedJobGeneration,
+		},
+	}
+	out.ObjectMeta.Namespace = ""
+	return out
+}
+
+// ToCombinedCluster converts a cluster-scoped v1alpha2 Cluster back into
+// its namespaced form, using Spec.Namespace as the namespace. It is the
+// inverse of FromCombinedCluster.
+func ToCombinedCluster(in *Cluster) (*controller.CombinedCluster, error) {
+	if in.Spec.Namespace == "" {
+		return nil, fmt.Errorf("cannot convert cluster-scoped Cluster %q to v1alpha1: spec.namespace is empty", in.Name)
+	}
+
+	cluster := &controller.CombinedCluster{}
+	cluster.ObjectMeta = *in.ObjectMeta.DeepCopy()
+	cluster.ObjectMeta.Namespace = in.Spec.Namespace
+	cluster.Spec.ClusterNetwork = in.Spec.ClusterNetwork
+	cluster.Spec.ProviderSpec = in.Spec.ProviderSpec
+	cluster.ClusterProviderStatus.Conditions = in.Status.Conditions
+	cluster.ClusterProviderStatus.Provisioned = in.Status.Provisioned
+	cluster.ClusterProviderStatus.ProvisionedJobGeneration = in.Status.ProvisionedJobGeneration
+	return cluster, nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	kapi "k8s.io/api/core/v1"
+
+	clusteroperator "github.com/openshift/cluster-operator/pkg/apis/clusteroperator/v1alpha1"
+	"github.com/openshift/cluster-operator/pkg/controller"
+
+	clusterapi "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// TestClusterRoundTrip exercises the v1alpha1 (namespaced) <->
+// v1alpha2 (cluster-scoped) Cluster conversion end to end: converting a
+// combined cluster to v1alpha2 and back must reproduce the original,
+// with the original namespace recovered from Spec.Namespace.
+func TestClusterRoundTrip(t *testing.T) {
+	original := &controller.CombinedCluster{
+		Cluster: clusterapi.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "test-cluster",
+				Namespace:  "test-namespace",
+				Generation: 3,
+			},
+			Spec: clusterapi.ClusterSpec{
+				ClusterNetwork: clusterapi.ClusterNetworkingConfig{
+					ServiceDomain: "cluster.local",
+				},
+				ProviderSpec: clusterapi.ProviderSpec{
+					Value: &runtime.RawExtension{Raw: []byte(`{"kind":"AWSClusterProviderConfig"}`)},
+				},
+			},
+		},
+		ClusterProviderStatus: clusteroperator.ClusterProviderStatus{
+			Conditions: []clusteroperator.ClusterCondition{
+				{
+					Type:   clusteroperator.ClusterInfraProvisioned,
+					Status: kapi.ConditionTrue,
+					Reason: "InfraProvisioned",
+				},
+			},
+			Provisioned:              true,
+			ProvisionedJobGeneration: 3,
+		},
+	}
+
+	converted := FromCombinedCluster(original)
+	if converted.Namespace != "" {
+		t.Fatalf("expected cluster-scoped conversion to clear metadata.namespace, got %q", converted.Namespace)
+	}
+	if converted.Spec.Namespace != original.Namespace {
+		t.Fatalf("expected spec.namespace %q, got %q", original.Namespace, converted.Spec.Namespace)
+	}
+
+	roundTripped, err := ToCombinedCluster(converted)
+	if err != nil {
+		t.Fatalf("unexpected error converting back to v1alpha1: %v", err)
+	}
+
+	if !reflect.DeepEqual(original.ObjectMeta, roundTripped.ObjectMeta) {
+		t.Errorf("ObjectMeta round trip mismatch:\noriginal:  %#v\nroundtrip: %#v", original.ObjectMeta, roundTripped.ObjectMeta)
+	}
+	if !reflect.DeepEqual(original.Spec.ProviderSpec, roundTripped.Spec.ProviderSpec) {
+		t.Errorf("Spec.ProviderSpec round trip mismatch:\noriginal:  %#v\nroundtrip: %#v", original.Spec.ProviderSpec, roundTripped.Spec.ProviderSpec)
+	}
+	if !reflect.DeepEqual(original.ClusterProviderStatus.Conditions, roundTripped.ClusterProviderStatus.Conditions) {
+		t.Errorf("Conditions round trip mismatch:\noriginal:  %#v\nroundtrip: %#v", original.ClusterProviderStatus.Conditions, roundTripped.ClusterProviderStatus.Conditions)
+	}
+	if !reflect.DeepEqual(original.Spec.ClusterNetwork, roundTripped.Spec.ClusterNetwork) {
+		t.Errorf("Spec.ClusterNetwork round trip mismatch:\noriginal:  %#v\nroundtrip: %#v", original.Spec.ClusterNetwork, roundTripped.Spec.ClusterNetwork)
+	}
+	if original.ClusterProviderStatus.Provisioned != roundTripped.ClusterProviderStatus.Provisioned {
+		t.Errorf("Provisioned round trip mismatch: original %v, roundtrip %v", original.ClusterProviderStatus.Provisioned, roundTripped.ClusterProviderStatus.Provisioned)
+	}
+	if original.ClusterProviderStatus.ProvisionedJobGeneration != roundTripped.ClusterProviderStatus.ProvisionedJobGeneration {
+		t.Errorf("ProvisionedJobGeneration round trip mismatch: original %v, roundtrip %v", original.ClusterProviderStatus.ProvisionedJobGeneration, roundTripped.ClusterProviderStatus.ProvisionedJobGeneration)
+	}
+}
+
+// TestToCombinedClusterRequiresNamespace verifies conversion back to
+// v1alpha1 fails loudly for a v1alpha2 Cluster that never had
+// Spec.Namespace populated, rather than silently producing an
+// unusable namespaced Cluster.
+func TestToCombinedClusterRequiresNamespace(t *testing.T) {
+	cluster := &Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "orphan-cluster"},
+	}
+
+	if _, err := ToCombinedCluster(cluster); err == nil {
+		t.Fatal("expected an error converting a Cluster with no spec.namespace, got nil")
+	}
+}
@@ -0,0 +1,25 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+// SetDefaults_ClusterSpec is read by defaulter-gen to build
+// SetObjectDefaults_Cluster/SetObjectDefaults_ClusterProviderConfigSpec
+// in zz_generated.defaults.go. ClusterSpec has no fields that need
+// defaulting of their own; this exists so those generated functions have
+// somewhere to nest future defaults.
+func SetDefaults_ClusterSpec(in *ClusterSpec) {
+}
@@ -0,0 +1,29 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha2 is the cluster-scoped successor to v1alpha1: Cluster
+// and ClusterProviderConfigSpec are registered without a namespace.
+// Conversion to and from v1alpha1 is handled by a webhook, which carries
+// the v1alpha1 object's namespace across as ClusterSpec.Namespace. The
+// round trip is lossless for the fields cluster-operator itself reads or
+// writes (ClusterSpec.ClusterNetwork, ClusterSpec.ProviderSpec, and
+// ClusterStatus); cluster-api Cluster.Status fields cluster-operator
+// does not use (APIEndpoints, ErrorReason, ErrorMessage) are not part of
+// ClusterStatus and are dropped.
+// +k8s:deepcopy-gen=package
+// +k8s:defaulter-gen=TypeMeta
+// +groupName=clusteroperator.openshift.io
+package v1alpha2
@@ -0,0 +1,98 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clusteroperator "github.com/openshift/cluster-operator/pkg/apis/clusteroperator/v1alpha1"
+
+	clusterapi "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Cluster is the v1alpha2, cluster-scoped representation of a
+// cluster-operator Cluster. Unlike v1alpha1.Cluster it carries no
+// metadata.namespace; ClusterSpec.Namespace records the namespace its
+// v1alpha1 counterpart lived in so the conversion webhook can round-trip
+// it during the scope migration.
+type Cluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterSpec   `json:"spec"`
+	Status ClusterStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterList is a list of Clusters.
+type ClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Cluster `json:"items"`
+}
+
+// ClusterSpec is the v1alpha2 cluster spec.
+type ClusterSpec struct {
+	// Namespace is the namespace the equivalent v1alpha1.Cluster lived
+	// in. Cluster-scoped v1alpha2 Clusters have no metadata.namespace of
+	// their own, so it is stored here instead.
+	Namespace string `json:"namespace"`
+
+	// ClusterNetwork is the cluster-api cluster-wide networking
+	// configuration, carried across unchanged by the move to cluster
+	// scope.
+	ClusterNetwork clusterapi.ClusterNetworkingConfig `json:"clusterNetwork,omitempty"`
+
+	// ProviderSpec is the provider-specific raw config
+	// (AWSClusterProviderConfig, GCPClusterProviderConfig, ...),
+	// unchanged by the move to cluster scope.
+	ProviderSpec clusterapi.ProviderSpec `json:"providerSpec,omitempty"`
+}
+
+// ClusterStatus is the v1alpha2 cluster status. It mirrors the fields of
+// clusteroperator.ClusterProviderStatus that live in a v1alpha1
+// Cluster's status.providerStatus raw extension.
+type ClusterStatus struct {
+	Conditions []clusteroperator.ClusterCondition `json:"conditions,omitempty"`
+
+	// Provisioned is true once the cluster's infrastructure has been
+	// successfully provisioned.
+	Provisioned bool `json:"provisioned,omitempty"`
+
+	// ProvisionedJobGeneration is the Cluster generation that was
+	// provisioned by the most recently run provisioning job.
+	ProvisionedJobGeneration int64 `json:"provisionedJobGeneration,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterProviderConfigSpec is the cluster-operator-specific portion of
+// a Cluster's ProviderSpec raw extension. Like Cluster, the v1alpha2
+// variant is registered cluster-scoped.
+type ClusterProviderConfigSpec struct {
+	metav1.TypeMeta `json:",inline"`
+
+	ClusterSpec `json:",inline"`
+}
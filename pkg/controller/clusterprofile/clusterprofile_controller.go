@@ -0,0 +1,329 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clusterprofile publishes a multicluster.x-k8s.io ClusterProfile
+// custom resource for every cluster whose infrastructure has been
+// provisioned, so that fleet tooling which understands the
+// ClusterProfile API (ArgoCD, KubeStellar, work-api, ...) can discover
+// cluster-operator-managed clusters without learning our internal CRDs.
+package clusterprofile
+
+import (
+	"fmt"
+	"time"
+
+	kapi "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/cluster-operator/pkg/controller"
+
+	clusteroperator "github.com/openshift/cluster-operator/pkg/apis/clusteroperator/v1alpha1"
+
+	clusterapi "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+	clusterapiinformers "sigs.k8s.io/cluster-api/pkg/client/informers_generated/externalversions/cluster/v1alpha1"
+	capilister "sigs.k8s.io/cluster-api/pkg/client/listers_generated/cluster/v1alpha1"
+)
+
+const (
+	controllerName = "clusterprofile"
+
+	// maxRetries mirrors the infra controller's retry budget; see the
+	// comment there for the resulting backoff sequence.
+	maxRetries = 15
+
+	// clusterManagerName is the value cluster-operator sets as
+	// spec.clusterManager.name on every ClusterProfile it publishes.
+	clusterManagerName = "cluster-operator"
+
+	conditionControlPlaneHealthy = "ControlPlaneHealthy"
+)
+
+// clusterProfileGVR identifies the ClusterProfile custom resource this
+// controller manages. There is no generated clientset for it yet, so it
+// is reconciled through a dynamic.Interface.
+var clusterProfileGVR = schema.GroupVersionResource{
+	Group:    "multicluster.x-k8s.io",
+	Version:  "v1alpha1",
+	Resource: "clusterprofiles",
+}
+
+// NewController returns a new *Controller that publishes ClusterProfiles
+// for provisioned clusters into profileNamespace.
+func NewController(
+	clusterInformer clusterapiinformers.ClusterInformer,
+	dynamicClient dynamic.Interface,
+	profileNamespace string,
+) *Controller {
+	logger := log.WithField("controller", controllerName)
+	c := &Controller{
+		dynamicClient:    dynamicClient,
+		profileNamespace: profileNamespace,
+		queue:            workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
+		logger:           logger,
+		clusterLister:    clusterInformer.Lister(),
+		clustersSynced:   clusterInformer.Informer().HasSynced,
+	}
+
+	clusterInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueCluster,
+		UpdateFunc: func(old, obj interface{}) { c.enqueueCluster(obj) },
+		DeleteFunc: c.enqueueCluster,
+	})
+
+	c.syncHandler = c.syncCluster
+
+	return c
+}
+
+// Controller mirrors provisioned clusters into ClusterProfile CRs.
+type Controller struct {
+	dynamicClient    dynamic.Interface
+	profileNamespace string
+
+	// To allow injection of syncCluster for testing.
+	syncHandler func(key string) error
+
+	clusterLister capilister.ClusterLister
+	// clustersSynced returns true if the cluster shared informer has
+	// been synced at least once. Added as a member to allow injection
+	// for testing.
+	clustersSynced cache.InformerSynced
+
+	// Clusters that need their ClusterProfile reconciled.
+	queue workqueue.RateLimitingInterface
+
+	logger *log.Entry
+}
+
+func (c *Controller) enqueueCluster(obj interface{}) {
+	cluster, ok := obj.(*clusterapi.Cluster)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("couldn't get object from tombstone %#v", obj))
+			return
+		}
+		cluster, ok = tombstone.Obj.(*clusterapi.Cluster)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("tombstone contained object that is not a Cluster %#v", obj))
+			return
+		}
+	}
+	key, err := controller.KeyFunc(cluster)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("couldn't get key for object %#v: %v", cluster, err))
+		return
+	}
+	c.queue.Add(key)
+}
+
+// Run runs c; will not return until stopCh is closed. workers determines
+// how many clusters will be handled in parallel.
+func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	c.logger.Infof("starting clusterprofile controller")
+	defer c.logger.Infof("shutting down clusterprofile controller")
+
+	if !controller.WaitForCacheSync(controllerName, stopCh, c.clustersSynced) {
+		c.logger.Errorf("could not sync caches for clusterprofile controller")
+		return
+	}
+
+	for i := 0; i < workers; i++ {
+		go wait.Until(c.worker, time.Second, stopCh)
+	}
+
+	<-stopCh
+}
+
+func (c *Controller) worker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *Controller) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	err := c.syncHandler(key.(string))
+	c.handleErr(err, key)
+
+	return true
+}
+
+func (c *Controller) handleErr(err error, key interface{}) {
+	if err == nil {
+		c.queue.Forget(key)
+		return
+	}
+
+	logger := c.logger.WithField("cluster", key)
+
+	logger.Errorf("error syncing cluster: %v", err)
+	if c.queue.NumRequeues(key) < maxRetries {
+		logger.Errorf("retrying cluster")
+		c.queue.AddRateLimited(key)
+		return
+	}
+
+	utilruntime.HandleError(err)
+	logger.Infof("dropping cluster out of the queue: %v", err)
+	c.queue.Forget(key)
+}
+
+// syncCluster creates, updates or garbage-collects the ClusterProfile
+// for a single cluster.
+func (c *Controller) syncCluster(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	cluster, err := c.clusterLister.Clusters(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return c.deleteClusterProfile(namespace, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	combinedCluster, err := controller.ConvertToCombinedCluster(cluster)
+	if err != nil {
+		return fmt.Errorf("could not convert cluster %s/%s into a combined cluster: %v", namespace, name, err)
+	}
+
+	provisioned := findClusterCondition(combinedCluster.ClusterProviderStatus.Conditions, clusteroperator.ClusterInfraProvisioned)
+	if provisioned == nil || provisioned.Status != kapi.ConditionTrue {
+		return c.deleteClusterProfile(namespace, name)
+	}
+
+	return c.applyClusterProfile(combinedCluster)
+}
+
+// profileName returns the name of the ClusterProfile that mirrors the
+// cluster identified by namespace/name. Profiles live in a single
+// shared profileNamespace, so the name is qualified by the source
+// cluster's namespace to keep same-named clusters in different
+// namespaces from colliding.
+func profileName(namespace, name string) string {
+	return fmt.Sprintf("%s-%s", namespace, name)
+}
+
+// applyClusterProfile creates or updates the ClusterProfile for a
+// provisioned cluster.
+func (c *Controller) applyClusterProfile(cluster *controller.CombinedCluster) error {
+	client := c.dynamicClient.Resource(clusterProfileGVR).Namespace(c.profileNamespace)
+	name := profileName(cluster.Namespace, cluster.Name)
+
+	spec := map[string]interface{}{
+		"displayName": cluster.Name,
+		"clusterManager": map[string]interface{}{
+			"name": clusterManagerName,
+		},
+		"credentialProvider": map[string]interface{}{
+			"secretRef": map[string]interface{}{
+				"namespace": cluster.Namespace,
+				"name":      fmt.Sprintf("%s-admin-kubeconfig", cluster.Name),
+			},
+		},
+	}
+	conditions := []interface{}{
+		map[string]interface{}{
+			"type":   conditionControlPlaneHealthy,
+			"status": string(controlPlaneHealthyStatus(cluster)),
+		},
+	}
+
+	existing, err := client.Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		profile := &unstructured.Unstructured{}
+		profile.SetGroupVersionKind(clusterProfileGVR.GroupVersion().WithKind("ClusterProfile"))
+		profile.SetNamespace(c.profileNamespace)
+		profile.SetName(name)
+		profile.Object["spec"] = spec
+		created, err := client.Create(profile)
+		if err != nil {
+			return err
+		}
+		if err := unstructured.SetNestedSlice(created.Object, conditions, "status", "conditions"); err != nil {
+			return fmt.Errorf("could not set status.conditions on ClusterProfile %s/%s: %v", c.profileNamespace, name, err)
+		}
+		_, err = client.UpdateStatus(created)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	existing.Object["spec"] = spec
+	updated, err := client.Update(existing)
+	if err != nil {
+		return err
+	}
+
+	if err := unstructured.SetNestedSlice(updated.Object, conditions, "status", "conditions"); err != nil {
+		return fmt.Errorf("could not set status.conditions on ClusterProfile %s/%s: %v", c.profileNamespace, name, err)
+	}
+	_, err = client.UpdateStatus(updated)
+	return err
+}
+
+// deleteClusterProfile garbage-collects the ClusterProfile for a cluster
+// that no longer exists or is no longer provisioned.
+func (c *Controller) deleteClusterProfile(namespace, name string) error {
+	client := c.dynamicClient.Resource(clusterProfileGVR).Namespace(c.profileNamespace)
+	err := client.Delete(profileName(namespace, name), &metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// controlPlaneHealthyStatus mirrors the infra controller's health-probe
+// condition onto the ClusterProfile's ControlPlaneHealthy condition.
+func controlPlaneHealthyStatus(cluster *controller.CombinedCluster) kapi.ConditionStatus {
+	healthy := findClusterCondition(cluster.ClusterProviderStatus.Conditions, clusteroperator.ClusterInfraReady)
+	if healthy == nil {
+		return kapi.ConditionUnknown
+	}
+	return healthy.Status
+}
+
+// findClusterCondition returns the condition of the given type, or nil
+// if the cluster does not yet have one.
+func findClusterCondition(conditions []clusteroperator.ClusterCondition, conditionType clusteroperator.ClusterConditionType) *clusteroperator.ClusterCondition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
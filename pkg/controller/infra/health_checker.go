@@ -0,0 +1,218 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package infra
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	kapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/clientcmd"
+
+	clusteroperator "github.com/openshift/cluster-operator/pkg/apis/clusteroperator/v1alpha1"
+	"github.com/openshift/cluster-operator/pkg/controller"
+)
+
+// probeClientBuilder builds the client used to probe a provisioned
+// cluster's control plane, given the raw contents of its admin
+// kubeconfig. Swapped out in unit tests to avoid dialing real clusters.
+type probeClientBuilder func(kubeconfig []byte) (discovery.DiscoveryInterface, error)
+
+// newDiscoveryClientForKubeconfig is the production probeClientBuilder. It
+// builds a discovery client from the admin kubeconfig pulled from the
+// cluster's admin secret.
+func newDiscoveryClientForKubeconfig(kubeconfig []byte) (discovery.DiscoveryInterface, error) {
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return discovery.NewDiscoveryClientForConfig(restConfig)
+}
+
+// checkClusterHealth probes every cluster whose infra has been
+// provisioned and reconciles the ClusterInfraReady condition
+// accordingly. It is run on a timer by Run. Probes run in parallel,
+// bounded by healthCheckConcurrency, so that one unreachable cluster's
+// dial does not delay every other cluster's probe until the next
+// interval.
+func (c *Controller) checkClusterHealth() {
+	clusters, err := c.clusterLister.List(labels.Everything())
+	if err != nil {
+		c.logger.Errorf("error listing clusters for health check: %v", err)
+		return
+	}
+
+	sem := make(chan struct{}, c.healthCheckConcurrency)
+	var wg sync.WaitGroup
+
+	for _, cluster := range clusters {
+		combinedCluster, err := controller.ConvertToCombinedCluster(cluster)
+		if err != nil {
+			c.logger.Warnf("could not convert cluster %s/%s for health check: %v", cluster.Namespace, cluster.Name, err)
+			continue
+		}
+		if !combinedCluster.ClusterProviderStatus.Provisioned {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(cluster *controller.CombinedCluster) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			c.probeAndUpdateCondition(cluster)
+		}(combinedCluster)
+	}
+
+	wg.Wait()
+}
+
+func (c *Controller) probeAndUpdateCondition(cluster *controller.CombinedCluster) {
+	key := fmt.Sprintf("%s/%s", cluster.Namespace, cluster.Name)
+	logger := c.logger.WithField("cluster", key)
+
+	err := c.probeClusterControlPlaneWithTimeout(cluster)
+
+	status := kapi.ConditionTrue
+	reason := reasonHealthCheckPassing
+	message := "cluster control plane health check passing"
+
+	// checkClusterHealth runs probeAndUpdateCondition for multiple
+	// clusters concurrently; consecutiveHealthFailures is shared state,
+	// so every access to it must go through the lock.
+	c.consecutiveHealthFailuresLock.Lock()
+	if err != nil {
+		c.consecutiveHealthFailures[key]++
+		failures := c.consecutiveHealthFailures[key]
+		c.consecutiveHealthFailuresLock.Unlock()
+
+		logger.Warnf("health check failed (%d/%d): %v", failures, c.healthCheckFailureThreshold, err)
+		if failures < c.healthCheckFailureThreshold {
+			return
+		}
+		status = kapi.ConditionFalse
+		reason = reasonHealthCheckFailed
+		message = fmt.Sprintf("cluster control plane health check failed: %v", err)
+	} else {
+		delete(c.consecutiveHealthFailures, key)
+		c.consecutiveHealthFailuresLock.Unlock()
+	}
+
+	// Re-fetch the cluster rather than persisting the lister/cache
+	// snapshot passed in: sync workers update this same cluster's status
+	// concurrently with the health check, so writing back conditions
+	// computed from a stale copy can revert their changes or spuriously
+	// conflict on resourceVersion.
+	liveCluster, err := c.caClient.ClusterV1alpha1().Clusters(cluster.Namespace).Get(cluster.Name, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("could not re-fetch cluster before updating health condition: %v", err)
+		return
+	}
+	liveCombinedCluster, err := controller.ConvertToCombinedCluster(liveCluster)
+	if err != nil {
+		logger.Errorf("could not convert freshly-fetched cluster %s: %v", key, err)
+		return
+	}
+
+	if existing := findClusterCondition(liveCombinedCluster.ClusterProviderStatus.Conditions, clusteroperator.ClusterInfraReady); existing != nil &&
+		existing.Status == status && existing.Reason == reason {
+		// No status or reason change: nothing to persist. A differing
+		// message alone is not written back.
+		return
+	}
+
+	liveCombinedCluster.ClusterProviderStatus.Conditions = controller.SetClusterCondition(
+		liveCombinedCluster.ClusterProviderStatus.Conditions,
+		clusteroperator.ClusterInfraReady,
+		status,
+		reason,
+		message,
+		controller.UpdateConditionAlways,
+	)
+
+	clusterAPICluster, err := controller.ClusterAPIClusterForCombinedCluster(liveCombinedCluster, false /*ignoreChanges*/)
+	if err != nil {
+		logger.Errorf("could not convert combined cluster back to a cluster-api cluster: %v", err)
+		return
+	}
+	if err := controller.UpdateClusterStatus(c.caClient, clusterAPICluster); err != nil {
+		logger.Errorf("could not update cluster status after health check: %v", err)
+		return
+	}
+
+	if status == kapi.ConditionFalse {
+		c.eventRecorder.Event(clusterAPICluster, kapi.EventTypeWarning, reasonHealthCheckFailed, message)
+	}
+}
+
+// findClusterCondition returns the condition of the given type, or nil if
+// the cluster does not yet have one.
+func findClusterCondition(conditions []clusteroperator.ClusterCondition, conditionType clusteroperator.ClusterConditionType) *clusteroperator.ClusterCondition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// probeClusterControlPlaneWithTimeout runs probeClusterControlPlane but
+// gives up after healthCheckProbeTimeout. discoveryClient.ServerVersion()
+// takes no context to cancel the dial, so an unreachable control plane
+// is bounded by racing it against a timer rather than by cancellation;
+// the probe goroutine is abandoned (and leaks until its own transport
+// timeout) if it loses the race.
+func (c *Controller) probeClusterControlPlaneWithTimeout(cluster *controller.CombinedCluster) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.probeClusterControlPlane(cluster)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(c.healthCheckProbeTimeout):
+		return fmt.Errorf("control plane health probe timed out after %s", c.healthCheckProbeTimeout)
+	}
+}
+
+// probeClusterControlPlane pulls the admin kubeconfig for the cluster and
+// issues a lightweight discovery call against the control plane.
+func (c *Controller) probeClusterControlPlane(cluster *controller.CombinedCluster) error {
+	secretName := fmt.Sprintf("%s-admin-kubeconfig", cluster.Name)
+	secret, err := c.kubeClient.CoreV1().Secrets(cluster.Namespace).Get(secretName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("could not get admin kubeconfig secret %s/%s: %v", cluster.Namespace, secretName, err)
+	}
+	kubeconfig, ok := secret.Data["kubeconfig"]
+	if !ok {
+		return fmt.Errorf("admin kubeconfig secret %s/%s has no kubeconfig key", cluster.Namespace, secretName)
+	}
+
+	discoveryClient, err := c.probeClientBuilder(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("could not build discovery client from admin kubeconfig: %v", err)
+	}
+	if _, err := discoveryClient.ServerVersion(); err != nil {
+		return fmt.Errorf("control plane discovery call failed: %v", err)
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ package infra
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	v1batch "k8s.io/api/batch/v1"
@@ -27,6 +28,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	batchinformers "k8s.io/client-go/informers/batch/v1"
 	kubeclientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
 	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
@@ -34,6 +36,7 @@ import (
 
 	"github.com/golang/glog"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 
 	"github.com/openshift/cluster-operator/pkg/ansible"
 	"github.com/openshift/cluster-operator/pkg/kubernetes/pkg/util/metrics"
@@ -61,20 +64,57 @@ const (
 
 	infraPlaybook            = "playbooks/cluster-operator/aws/infrastructure.yml"
 	deprovisionInfraPlaybook = "playbooks/cluster-operator/aws/uninstall_infrastructure.yml"
+
+	// defaultHealthCheckInterval is how often a provisioned cluster's
+	// control plane is probed when the controller is not given an
+	// explicit interval.
+	defaultHealthCheckInterval = 30 * time.Second
+
+	// defaultHealthCheckFailureThreshold is the number of consecutive
+	// failed probes required before a cluster is marked unhealthy.
+	defaultHealthCheckFailureThreshold = 3
+
+	// defaultHealthCheckProbeTimeout bounds a single cluster's control
+	// plane probe so that one unreachable cluster cannot block the
+	// health check of every other cluster for the full interval.
+	defaultHealthCheckProbeTimeout = 10 * time.Second
+
+	// defaultHealthCheckConcurrency is how many clusters' control planes
+	// are probed in parallel by a single checkClusterHealth run.
+	defaultHealthCheckConcurrency = 10
+
+	reasonHealthCheckFailed  = "HealthCheckFailed"
+	reasonHealthCheckPassing = "HealthCheckPassing"
 )
 
 var (
 	clusterKind = clusterapi.SchemeGroupVersion.WithKind("Cluster")
 )
 
+// newRateLimiter builds the infra controller's queue rate limiter: a
+// per-item exponential backoff (5ms up to a 15 minute ceiling per
+// cluster) combined with an overall token-bucket limiter so that one
+// badly-behaved cluster cannot starve the rest of the queue.
+func newRateLimiter() workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(5*time.Millisecond, 15*time.Minute),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	)
+}
+
 // NewController returns a new *Controller to use with
-// cluster-api resources.
+// cluster-api resources. If providerRegistry is nil, DefaultProviderRegistry
+// is used. defaultInfraRunner selects which InfraRunner clusters use when
+// they do not carry the infraRunnerAnnotation override; if empty,
+// InfraRunnerAnsible is used.
 func NewController(
 	clusterInformer clusterapiinformers.ClusterInformer,
 	jobInformer batchinformers.JobInformer,
 	kubeClient kubeclientset.Interface,
 	clusteroperatorClient clusteroperatorclientset.Interface,
 	clusterapiClient clusterapiclientset.Interface,
+	providerRegistry *ProviderRegistry,
+	defaultInfraRunner InfraRunnerName,
 ) *Controller {
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartLogging(glog.Infof)
@@ -88,15 +128,26 @@ func NewController(
 		)
 	}
 
+	if providerRegistry == nil {
+		providerRegistry = DefaultProviderRegistry()
+	}
+	if defaultInfraRunner == "" {
+		defaultInfraRunner = InfraRunnerAnsible
+	}
+
 	logger := log.WithField("controller", controllerName)
 	c := &Controller{
-		coClient:       clusteroperatorClient,
-		caClient:       clusterapiClient,
-		kubeClient:     kubeClient,
-		queue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
-		logger:         logger,
-		clusterLister:  clusterInformer.Lister(),
-		clustersSynced: clusterInformer.Informer().HasSynced,
+		coClient:         clusteroperatorClient,
+		caClient:         clusterapiClient,
+		kubeClient:       kubeClient,
+		queue:            workqueue.NewNamedRateLimitingQueue(newRateLimiter(), controllerName),
+		logger:           logger,
+		clusterLister:    clusterInformer.Lister(),
+		clustersSynced:   clusterInformer.Informer().HasSynced,
+		providerRegistry: providerRegistry,
+		retryLimit:       maxRetries,
+
+		consecutiveHealthFailures: make(map[string]int),
 	}
 
 	clusterInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -116,6 +167,18 @@ func NewController(
 	c.enqueueCluster = c.enqueue
 	c.ansibleGenerator = ansible.NewJobGenerator()
 
+	c.defaultInfraRunner = defaultInfraRunner
+	c.infraRunners = map[InfraRunnerName]InfraRunner{
+		InfraRunnerAnsible: &ansibleInfraRunner{controller: c},
+	}
+
+	c.healthCheckInterval = defaultHealthCheckInterval
+	c.healthCheckFailureThreshold = defaultHealthCheckFailureThreshold
+	c.healthCheckProbeTimeout = defaultHealthCheckProbeTimeout
+	c.healthCheckConcurrency = defaultHealthCheckConcurrency
+	c.probeClientBuilder = newDiscoveryClientForKubeconfig
+	c.eventRecorder = eventBroadcaster.NewRecorder(scheme.Scheme, kapi.EventSource{Component: fmt.Sprintf("clusteroperator-%s-controller", controllerName)})
+
 	return c
 }
 
@@ -138,6 +201,18 @@ type Controller struct {
 	// used for unit testing
 	enqueueCluster func(cluster metav1.Object)
 
+	// providerRegistry looks up the playbooks and OpenShift-version
+	// accessor for the infra provider named by a cluster's ProviderSpec.
+	providerRegistry *ProviderRegistry
+
+	// infraRunners holds the InfraRunner implementations available to
+	// clusters, keyed by name.
+	infraRunners map[InfraRunnerName]InfraRunner
+
+	// defaultInfraRunner is used for clusters that do not carry the
+	// infraRunnerAnnotation override.
+	defaultInfraRunner InfraRunnerName
+
 	clusterLister capilister.ClusterLister
 	// clustersSynced returns true if the cluster shared informer has been synced at least once.
 	// Added as a member to the struct to allow injection for testing.
@@ -149,7 +224,43 @@ type Controller struct {
 	// Clusters that need to be synced
 	queue workqueue.RateLimitingInterface
 
+	// retryLimit is the number of times a cluster will be retried
+	// before it is dropped out of the queue. Defaults to maxRetries;
+	// injectable for unit tests.
+	retryLimit int
+
 	logger *log.Entry
+
+	// healthCheckInterval is how often provisioned clusters are probed
+	// for control plane health. Injectable for unit tests.
+	healthCheckInterval time.Duration
+
+	// healthCheckFailureThreshold is the number of consecutive failed
+	// probes required before a cluster is marked unhealthy.
+	healthCheckFailureThreshold int
+
+	// healthCheckProbeTimeout bounds how long a single cluster's control
+	// plane probe is allowed to run before it is treated as a failure.
+	healthCheckProbeTimeout time.Duration
+
+	// healthCheckConcurrency is how many clusters are probed in parallel
+	// by a single checkClusterHealth run.
+	healthCheckConcurrency int
+
+	// probeClientBuilder builds the client used to probe a cluster's
+	// control plane from its admin kubeconfig. Injectable for unit tests.
+	probeClientBuilder probeClientBuilder
+
+	// consecutiveHealthFailuresLock guards consecutiveHealthFailures,
+	// which is read and written concurrently by the per-cluster health
+	// check goroutines checkClusterHealth fans out.
+	consecutiveHealthFailuresLock sync.Mutex
+
+	// consecutiveHealthFailures tracks, per cluster key, how many probes
+	// in a row have failed.
+	consecutiveHealthFailures map[string]int
+
+	eventRecorder record.EventRecorder
 }
 
 func (c *Controller) addCluster(obj interface{}) {
@@ -200,6 +311,8 @@ func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
 		go wait.Until(c.worker, time.Second, stopCh)
 	}
 
+	go wait.Until(c.checkClusterHealth, c.healthCheckInterval, stopCh)
+
 	<-stopCh
 }
 
@@ -227,7 +340,10 @@ func (c *Controller) processNextWorkItem() bool {
 	}
 	defer c.queue.Done(key)
 
+	start := time.Now()
 	err := c.syncHandler(key.(string))
+	syncDuration.Observe(time.Since(start).Seconds())
+
 	c.handleErr(err, key)
 
 	return true
@@ -240,14 +356,17 @@ func (c *Controller) handleErr(err error, key interface{}) {
 	}
 
 	logger := c.logger.WithField("cluster", key)
+	reason := classifyError(err)
+	requeueTotal.WithLabelValues(reason).Inc()
 
 	logger.Errorf("error syncing cluster: %v", err)
-	if c.queue.NumRequeues(key) < maxRetries {
+	if c.queue.NumRequeues(key) < c.retryLimit {
 		logger.Errorf("retrying cluster")
 		c.queue.AddRateLimited(key)
 		return
 	}
 
+	droppedTotal.Inc()
 	utilruntime.HandleError(err)
 	logger.Infof("dropping cluster out of the queue: %v", err)
 	c.queue.Forget(key)
@@ -288,8 +407,15 @@ func (s *jobSyncStrategy) GetOwner(key string) (metav1.Object, error) {
 	if err != nil {
 		return nil, err
 	}
-	if len(namespace) == 0 || len(name) == 0 {
-		return nil, fmt.Errorf("invalid key %q: either namespace or name is missing", key)
+	if len(name) == 0 {
+		return nil, fmt.Errorf("invalid key %q: name is missing", key)
+	}
+	if len(namespace) == 0 {
+		// A bare-name key identifies a cluster-scoped v1alpha2 Cluster.
+		// clusterLister only knows the namespaced v1alpha1 Clusters, so
+		// during the v1alpha2 rollout these cannot yet be reconciled
+		// here; fail loudly rather than silently dropping them.
+		return nil, fmt.Errorf("cluster-scoped cluster %q: v1alpha2 infra reconciliation is not yet supported", name)
 	}
 	cluster, err := s.controller.clusterLister.Clusters(namespace).Get(name)
 	if err != nil {
@@ -313,21 +439,19 @@ func (s *jobSyncStrategy) GetJobFactory(owner metav1.Object, deleting bool) (con
 	if err != nil {
 		return nil, fmt.Errorf("could not convert owner from JobSync into a cluster: %v: %#v", err, owner)
 	}
-	cv := cluster.AWSClusterProviderConfig.OpenShiftConfig.Version
 
-	infraSize, err := controller.GetInfraSize(cluster)
+	providerName, err := providerNameForCluster(cluster)
 	if err != nil {
-		return nil, fmt.Errorf("could not get the infra size: %v", err)
+		return nil, err
 	}
-	playbook := infraPlaybook
-	if deleting {
-		playbook = deprovisionInfraPlaybook
+	provider, ok := s.controller.providerRegistry.Get(providerName)
+	if !ok {
+		return nil, fmt.Errorf("no infra provider registered for %q (cluster %s/%s)", providerName, cluster.Namespace, cluster.Name)
 	}
-	jobGeneratorExecutor := ansible.
-		NewJobGeneratorExecutorForMasterMachineSet(s.controller.ansibleGenerator, []string{playbook}, cluster, cv).
-		WithInfraSize(infraSize)
+	runner := s.controller.infraRunnerForCluster(cluster)
+
 	return jobFactory(func(name string) (*v1batch.Job, *kapi.ConfigMap, error) {
-		job, configMap, err := jobGeneratorExecutor.Execute(name)
+		job, configMap, err := runner.BuildJob(cluster, provider, deleting, name)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -0,0 +1,88 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package infra
+
+import (
+	"fmt"
+
+	v1batch "k8s.io/api/batch/v1"
+	kapi "k8s.io/api/core/v1"
+
+	"github.com/openshift/cluster-operator/pkg/ansible"
+	"github.com/openshift/cluster-operator/pkg/controller"
+)
+
+// InfraRunnerName identifies which tool the infra controller uses to
+// provision and deprovision a cluster's infrastructure.
+type InfraRunnerName string
+
+const (
+	// InfraRunnerAnsible runs the provider's ansible playbooks. This is
+	// the only runner that existed before InfraRunner was introduced,
+	// and remains the default.
+	InfraRunnerAnsible InfraRunnerName = "ansible"
+
+	// infraRunnerAnnotation overrides the controller-wide default
+	// runner for a single cluster.
+	infraRunnerAnnotation = "cluster-operator.openshift.io/infra-runner"
+)
+
+// InfraRunner builds the Job and ConfigMap used to provision or
+// deprovision a single cluster's infrastructure.
+type InfraRunner interface {
+	BuildJob(cluster *controller.CombinedCluster, provider Provider, deleting bool, name string) (*v1batch.Job, *kapi.ConfigMap, error)
+}
+
+// infraRunnerForCluster returns the InfraRunner the cluster should use:
+// the cluster's infraRunnerAnnotation override if set and registered,
+// otherwise the controller's default runner.
+func (c *Controller) infraRunnerForCluster(cluster *controller.CombinedCluster) InfraRunner {
+	if name := InfraRunnerName(cluster.Annotations[infraRunnerAnnotation]); name != "" {
+		if runner, ok := c.infraRunners[name]; ok {
+			return runner
+		}
+	}
+	if runner, ok := c.infraRunners[c.defaultInfraRunner]; ok {
+		return runner
+	}
+	return c.infraRunners[InfraRunnerAnsible]
+}
+
+// ansibleInfraRunner is the InfraRunner that existed before pluggable
+// runners were introduced: it drives ansible-playbook against the
+// provider's provision/deprovision playbooks. The generator is read from
+// the controller's ansibleGenerator field each time a job is built.
+type ansibleInfraRunner struct {
+	controller *Controller
+}
+
+func (r *ansibleInfraRunner) BuildJob(cluster *controller.CombinedCluster, provider Provider, deleting bool, name string) (*v1batch.Job, *kapi.ConfigMap, error) {
+	cv := provider.OpenShiftVersion(cluster)
+
+	infraSize, err := controller.GetInfraSize(cluster)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get the infra size: %v", err)
+	}
+	playbook := provider.ProvisionPlaybook
+	if deleting {
+		playbook = provider.DeprovisionPlaybook
+	}
+	jobGeneratorExecutor := ansible.
+		NewJobGeneratorExecutorForMasterMachineSet(r.controller.ansibleGenerator, []string{playbook}, cluster, cv).
+		WithInfraSize(infraSize)
+	return jobGeneratorExecutor.Execute(name)
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package infra
+
+import (
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	errorReasonAnsibleJob = "ansible_job_failure"
+	errorReasonConversion = "conversion_error"
+	errorReasonAPIServer  = "apiserver_error"
+	errorReasonOther      = "other"
+)
+
+var (
+	requeueTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "clusteroperator_infra_requeue_total",
+		Help: "Number of times a cluster was requeued by the infra controller, by error reason.",
+	}, []string{"reason"})
+
+	droppedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "clusteroperator_infra_dropped_total",
+		Help: "Number of clusters dropped from the infra controller's queue after exceeding the retry limit.",
+	})
+
+	syncDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "clusteroperator_infra_sync_duration_seconds",
+		Help:    "Time taken to sync a single cluster in the infra controller.",
+		Buckets: prometheus.DefBuckets,
+	})
+)
+
+func init() {
+	prometheus.MustRegister(requeueTotal, droppedTotal, syncDuration)
+}
+
+// classifyError buckets a sync error into a coarse reason so that
+// ansible job failures can be alerted on separately from transient
+// apiserver errors and cluster/owner conversion bugs.
+func classifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "ansible"), strings.Contains(msg, "job failed"):
+		return errorReasonAnsibleJob
+	case strings.Contains(msg, "could not convert"):
+		return errorReasonConversion
+	case apierrors.IsServerTimeout(err), apierrors.IsTimeout(err), apierrors.IsTooManyRequests(err), apierrors.IsInternalError(err):
+		return errorReasonAPIServer
+	default:
+		return errorReasonOther
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package infra
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/openshift/cluster-operator/pkg/controller"
+)
+
+// ProviderName identifies a supported infrastructure provider.
+type ProviderName string
+
+const (
+	ProviderAWS       ProviderName = "aws"
+	ProviderGCP       ProviderName = "gcp"
+	ProviderAzure     ProviderName = "azure"
+	ProviderOpenStack ProviderName = "openstack"
+)
+
+// Provider wires a single infrastructure provider into the infra
+// controller: the playbooks used to provision/deprovision its
+// infrastructure, and how to read the OpenShift version out of its
+// provider config. Infra sizing is computed by controller.GetInfraSize,
+// which is shared across providers.
+type Provider struct {
+	ProvisionPlaybook   string
+	DeprovisionPlaybook string
+	OpenShiftVersion    func(cluster *controller.CombinedCluster) string
+}
+
+// ProviderRegistry maps a provider name, as determined from a Cluster's
+// Spec.ProviderSpec, to its Provider. GetJobFactory looks providers up
+// here instead of hard-coding AWS, so other clouds can be added without
+// touching the job-sync strategy.
+type ProviderRegistry struct {
+	providers map[ProviderName]Provider
+}
+
+// NewProviderRegistry returns an empty ProviderRegistry.
+func NewProviderRegistry() *ProviderRegistry {
+	return &ProviderRegistry{providers: make(map[ProviderName]Provider)}
+}
+
+// Register adds or replaces the Provider for the given name.
+func (r *ProviderRegistry) Register(name ProviderName, provider Provider) {
+	r.providers[name] = provider
+}
+
+// Get returns the Provider registered for name, if any.
+func (r *ProviderRegistry) Get(name ProviderName) (Provider, bool) {
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// DefaultProviderRegistry returns the ProviderRegistry cluster-operator
+// ships with out of the box: AWS only, matching behavior from before
+// multi-cloud dispatch existed. GCP, Azure and OpenStack can be
+// registered by callers once job-generation support for them lands.
+func DefaultProviderRegistry() *ProviderRegistry {
+	r := NewProviderRegistry()
+	r.Register(ProviderAWS, Provider{
+		ProvisionPlaybook:   infraPlaybook,
+		DeprovisionPlaybook: deprovisionInfraPlaybook,
+		OpenShiftVersion: func(cluster *controller.CombinedCluster) string {
+			return cluster.AWSClusterProviderConfig.OpenShiftConfig.Version
+		},
+	})
+	return r
+}
+
+// providerSpecKind is the minimal shape every provider's ProviderSpec
+// raw extension shares: a discriminating Kind field.
+type providerSpecKind struct {
+	Kind string `json:"kind"`
+}
+
+var providerKindToName = map[string]ProviderName{
+	"AWSClusterProviderConfig":       ProviderAWS,
+	"GCPClusterProviderConfig":       ProviderGCP,
+	"AzureClusterProviderConfig":     ProviderAzure,
+	"OpenstackClusterProviderConfig": ProviderOpenStack,
+}
+
+// providerNameForCluster inspects the cluster's ProviderSpec to
+// determine which provider owns its infrastructure.
+func providerNameForCluster(cluster *controller.CombinedCluster) (ProviderName, error) {
+	if cluster.Spec.ProviderSpec.Value == nil {
+		return "", fmt.Errorf("cluster %s/%s has no providerSpec set", cluster.Namespace, cluster.Name)
+	}
+	var kind providerSpecKind
+	if err := json.Unmarshal(cluster.Spec.ProviderSpec.Value.Raw, &kind); err != nil {
+		return "", fmt.Errorf("could not determine provider for cluster %s/%s: %v", cluster.Namespace, cluster.Name, err)
+	}
+	if name, ok := providerKindToName[kind.Kind]; ok {
+		return name, nil
+	}
+
+	// Clusters from before multi-cloud dispatch existed may not carry a
+	// "kind" discriminator in their providerSpec raw extension, even
+	// though ConvertToCombinedCluster still decodes it into
+	// AWSClusterProviderConfig. Fall back to that rather than failing
+	// job generation for the only provider this controller has ever
+	// supported.
+	if cluster.AWSClusterProviderConfig.OpenShiftConfig.Version != "" {
+		return ProviderAWS, nil
+	}
+
+	return "", fmt.Errorf("cluster %s/%s has unrecognized providerSpec kind %q", cluster.Namespace, cluster.Name, kind.Kind)
+}
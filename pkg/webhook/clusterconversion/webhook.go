@@ -0,0 +1,150 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clusterconversion implements the CRD conversion webhook that
+// lets the apiserver serve both v1alpha1 (namespaced) and v1alpha2
+// (cluster-scoped) Clusters during the scope migration described in
+// pkg/apis/clusteroperator/v1alpha2.
+package clusterconversion
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	log "github.com/sirupsen/logrus"
+
+	v1alpha1 "github.com/openshift/cluster-operator/pkg/apis/clusteroperator/v1alpha1"
+	v1alpha2 "github.com/openshift/cluster-operator/pkg/apis/clusteroperator/v1alpha2"
+	"github.com/openshift/cluster-operator/pkg/controller"
+
+	clusterapi "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// ServeHTTP implements the apiextensions ConversionReview protocol for
+// Cluster: it is registered as the conversion webhook in the Cluster
+// CustomResourceDefinition.
+func ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	review := &apiextensionsv1beta1.ConversionReview{}
+	if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+		http.Error(w, fmt.Sprintf("could not decode ConversionReview: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	review.Response = convert(review.Request)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		log.Errorf("could not encode ConversionReview response: %v", err)
+	}
+}
+
+func convert(request *apiextensionsv1beta1.ConversionRequest) *apiextensionsv1beta1.ConversionResponse {
+	response := &apiextensionsv1beta1.ConversionResponse{
+		UID:    request.UID,
+		Result: metav1.Status{Status: metav1.StatusSuccess},
+	}
+
+	converted := make([]runtime.RawExtension, 0, len(request.Objects))
+	for _, obj := range request.Objects {
+		out, err := convertObject(obj, request.DesiredAPIVersion)
+		if err != nil {
+			response.Result = metav1.Status{
+				Status:  metav1.StatusFailure,
+				Message: err.Error(),
+			}
+			return response
+		}
+		converted = append(converted, out)
+	}
+
+	response.ConvertedObjects = converted
+	return response
+}
+
+func convertObject(in runtime.RawExtension, desiredAPIVersion string) (runtime.RawExtension, error) {
+	switch desiredAPIVersion {
+	case v1alpha1.SchemeGroupVersion.String():
+		return convertToV1alpha1(in)
+	case v1alpha2.SchemeGroupVersion.String():
+		return convertToV1alpha2(in)
+	default:
+		return runtime.RawExtension{}, fmt.Errorf("unsupported desired apiVersion %q", desiredAPIVersion)
+	}
+}
+
+// convertToV1alpha2 converts a namespaced v1alpha1 Cluster into its
+// cluster-scoped v1alpha2 representation. It decodes into a
+// clusterapi.Cluster and goes through controller.ConvertToCombinedCluster,
+// the same helper every other caller in this tree uses to assemble a
+// CombinedCluster, because ClusterProviderStatus lives in the object's
+// status.providerStatus raw extension rather than a plain top-level
+// field: a bare json.Unmarshal into CombinedCluster leaves Conditions
+// empty and silently drops status on every conversion.
+func convertToV1alpha2(in runtime.RawExtension) (runtime.RawExtension, error) {
+	cluster := &clusterapi.Cluster{}
+	if err := json.Unmarshal(in.Raw, cluster); err != nil {
+		return runtime.RawExtension{}, fmt.Errorf("could not decode v1alpha1 Cluster: %v", err)
+	}
+
+	combined, err := controller.ConvertToCombinedCluster(cluster)
+	if err != nil {
+		return runtime.RawExtension{}, fmt.Errorf("could not convert v1alpha1 Cluster %q: %v", cluster.Name, err)
+	}
+
+	out := v1alpha2.FromCombinedCluster(combined)
+	raw, err := json.Marshal(out)
+	if err != nil {
+		return runtime.RawExtension{}, fmt.Errorf("could not encode v1alpha2 Cluster: %v", err)
+	}
+	return runtime.RawExtension{Raw: raw}, nil
+}
+
+// convertToV1alpha1 converts a cluster-scoped v1alpha2 Cluster back into
+// its namespaced v1alpha1 representation, going through
+// controller.ClusterAPIClusterForCombinedCluster (the inverse of
+// ConvertToCombinedCluster) so status.providerStatus is re-encoded the
+// same way every other writer of a v1alpha1 Cluster encodes it, rather
+// than marshaling the internal CombinedCluster directly.
+func convertToV1alpha1(in runtime.RawExtension) (runtime.RawExtension, error) {
+	cluster := &v1alpha2.Cluster{}
+	if err := json.Unmarshal(in.Raw, cluster); err != nil {
+		return runtime.RawExtension{}, fmt.Errorf("could not decode v1alpha2 Cluster: %v", err)
+	}
+
+	combined, err := v1alpha2.ToCombinedCluster(cluster)
+	if err != nil {
+		return runtime.RawExtension{}, fmt.Errorf("could not convert v1alpha2 Cluster %q: %v", cluster.Name, err)
+	}
+
+	out, err := controller.ClusterAPIClusterForCombinedCluster(combined, false /*ignoreChanges*/)
+	if err != nil {
+		return runtime.RawExtension{}, fmt.Errorf("could not convert combined cluster %q to v1alpha1: %v", cluster.Name, err)
+	}
+	out.TypeMeta = metav1.TypeMeta{
+		APIVersion: v1alpha1.SchemeGroupVersion.String(),
+		Kind:       "Cluster",
+	}
+	raw, err := json.Marshal(out)
+	if err != nil {
+		return runtime.RawExtension{}, fmt.Errorf("could not encode v1alpha1 Cluster: %v", err)
+	}
+	return runtime.RawExtension{Raw: raw}, nil
+}
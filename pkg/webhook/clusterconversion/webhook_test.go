@@ -0,0 +1,112 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterconversion
+
+import (
+	"encoding/json"
+	"testing"
+
+	kapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	clusteroperator "github.com/openshift/cluster-operator/pkg/apis/clusteroperator/v1alpha1"
+	v1alpha2 "github.com/openshift/cluster-operator/pkg/apis/clusteroperator/v1alpha2"
+
+	clusterapi "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha1"
+)
+
+// TestConvertObjectRoundTripsStatus exercises convertObject over the same
+// wire encoding the apiserver sends: a v1alpha1 Cluster with its
+// ClusterProviderStatus encoded into status.providerStatus, the way it is
+// actually stored, rather than constructing a controller.CombinedCluster
+// in memory. TestClusterRoundTrip in the v1alpha2 package already covers
+// FromCombinedCluster/ToCombinedCluster directly; this test guards the
+// webhook's decode/encode path, which previously dropped Conditions by
+// unmarshaling straight into CombinedCluster.
+func TestConvertObjectRoundTripsStatus(t *testing.T) {
+	providerStatus, err := json.Marshal(&clusteroperator.ClusterProviderStatus{
+		Conditions: []clusteroperator.ClusterCondition{
+			{
+				Type:   clusteroperator.ClusterInfraProvisioned,
+				Status: kapi.ConditionTrue,
+				Reason: "InfraProvisioned",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("could not marshal ClusterProviderStatus: %v", err)
+	}
+
+	original := &clusterapi.Cluster{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "cluster.k8s.io/v1alpha1",
+			Kind:       "Cluster",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-cluster",
+			Namespace: "test-namespace",
+		},
+		Status: clusterapi.ClusterStatus{
+			ProviderStatus: &runtime.RawExtension{Raw: providerStatus},
+		},
+	}
+	originalRaw, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("could not marshal original v1alpha1 Cluster: %v", err)
+	}
+
+	v1alpha2Raw, err := convertObject(runtime.RawExtension{Raw: originalRaw}, v1alpha2.SchemeGroupVersion.String())
+	if err != nil {
+		t.Fatalf("could not convert to v1alpha2: %v", err)
+	}
+
+	converted := &v1alpha2.Cluster{}
+	if err := json.Unmarshal(v1alpha2Raw.Raw, converted); err != nil {
+		t.Fatalf("could not decode converted v1alpha2 Cluster: %v", err)
+	}
+	if converted.APIVersion != v1alpha2.SchemeGroupVersion.String() || converted.Kind != "Cluster" {
+		t.Errorf("expected v1alpha2 TypeMeta to be set, got %#v", converted.TypeMeta)
+	}
+	if len(converted.Status.Conditions) != 1 || converted.Status.Conditions[0].Type != clusteroperator.ClusterInfraProvisioned {
+		t.Fatalf("expected condition %q to survive conversion to v1alpha2, got %#v", clusteroperator.ClusterInfraProvisioned, converted.Status.Conditions)
+	}
+
+	v1alpha1Raw, err := convertObject(*v1alpha2Raw, clusteroperator.SchemeGroupVersion.String())
+	if err != nil {
+		t.Fatalf("could not convert back to v1alpha1: %v", err)
+	}
+
+	roundTripped := &clusterapi.Cluster{}
+	if err := json.Unmarshal(v1alpha1Raw.Raw, roundTripped); err != nil {
+		t.Fatalf("could not decode round-tripped v1alpha1 Cluster: %v", err)
+	}
+	if roundTripped.APIVersion != clusteroperator.SchemeGroupVersion.String() || roundTripped.Kind != "Cluster" {
+		t.Errorf("expected v1alpha1 TypeMeta to be set, got %#v", roundTripped.TypeMeta)
+	}
+	if roundTripped.Status.ProviderStatus == nil {
+		t.Fatal("expected status.providerStatus to survive the round trip, got nil")
+	}
+
+	roundTrippedStatus := &clusteroperator.ClusterProviderStatus{}
+	if err := json.Unmarshal(roundTripped.Status.ProviderStatus.Raw, roundTrippedStatus); err != nil {
+		t.Fatalf("could not decode round-tripped ClusterProviderStatus: %v", err)
+	}
+	if len(roundTrippedStatus.Conditions) != 1 || roundTrippedStatus.Conditions[0].Type != clusteroperator.ClusterInfraProvisioned {
+		t.Fatalf("expected condition %q to survive the full round trip, got %#v", clusteroperator.ClusterInfraProvisioned, roundTrippedStatus.Conditions)
+	}
+}